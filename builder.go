@@ -0,0 +1,145 @@
+package dpop
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/url"
+	"time"
+
+	"github.com/cloudflare/circl/sign/ed448"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// CreateOptions are optional parameters for Create.
+type CreateOptions struct {
+	// Nonce is the `nonce` claim, required once a server has asked for one. See
+	// https://datatracker.ietf.org/doc/html/rfc9449#section-8.
+	Nonce string
+
+	// AccessToken, if set, binds the proof to a previously issued access token by
+	// populating the `ath` claim with base64url(sha256(AccessToken)), per
+	// https://datatracker.ietf.org/doc/html/rfc9449#section-4.3
+	AccessToken string
+
+	// IssuedAt overrides the `iat` claim. If zero, time.Now() is used.
+	IssuedAt time.Time
+
+	// JTI overrides the `jti` claim. If empty, a random jti is generated.
+	JTI string
+}
+
+// Create mints a signed DPoP proof (a `dpop+jwt` token) for the given HTTP method and URL,
+// signed by privateKey. It is the counterpart to Parse: a client calls Create to attach a
+// proof to an outgoing request, and a server calls Parse to validate one.
+//
+// privateKey must be one of *ecdsa.PrivateKey (P-256, P-384, P-521 or secp256k1),
+// *rsa.PrivateKey, ed25519.PrivateKey, or ed448.PrivateKey.
+func Create(
+	privateKey interface{},
+	httpMethod HTTPVerb,
+	httpURL *url.URL,
+	opts CreateOptions,
+) (string, error) {
+	signingMethod, publicKey, err := signingMethodFor(privateKey)
+	if err != nil {
+		return "", err
+	}
+
+	jwkHeaderBytes, err := getKeyStringRepresentation(publicKey)
+	if err != nil {
+		return "", err
+	}
+	var jwkHeader map[string]interface{}
+	if err := json.Unmarshal(jwkHeaderBytes, &jwkHeader); err != nil {
+		return "", err
+	}
+
+	// Don't modify the caller's URL. Strip query and fragment per
+	// https://datatracker.ietf.org/doc/html/rfc9449#section-4.2
+	strippedURL := *httpURL
+	strippedURL.RawQuery = ""
+	strippedURL.Fragment = ""
+
+	issuedAt := opts.IssuedAt
+	if issuedAt.IsZero() {
+		issuedAt = time.Now()
+	}
+
+	jti := opts.JTI
+	if jti == "" {
+		jti, err = newJTI()
+		if err != nil {
+			return "", err
+		}
+	}
+
+	claims := ProofTokenClaims{
+		RegisteredClaims: &jwt.RegisteredClaims{
+			ID:       jti,
+			IssuedAt: jwt.NewNumericDate(issuedAt),
+		},
+		Method: httpMethod,
+		URL:    strippedURL.String(),
+		Nonce:  opts.Nonce,
+	}
+	if opts.AccessToken != "" {
+		claims.Ath = athHash(opts.AccessToken)
+	}
+
+	token := jwt.NewWithClaims(signingMethod, &claims)
+	token.Header["typ"] = "dpop+jwt"
+	token.Header["jwk"] = jwkHeader
+
+	return token.SignedString(privateKey)
+}
+
+// signingMethodFor picks the jwt.SigningMethod and derives the public key to advertise in the
+// `jwk` header for a given DPoP private key.
+func signingMethodFor(privateKey interface{}) (jwt.SigningMethod, interface{}, error) {
+	switch key := privateKey.(type) {
+	case *ecdsa.PrivateKey:
+		switch key.Curve.Params().Name {
+		case "P-256":
+			return jwt.SigningMethodES256, &key.PublicKey, nil
+		case "P-384":
+			return jwt.SigningMethodES384, &key.PublicKey, nil
+		case "P-521":
+			return jwt.SigningMethodES512, &key.PublicKey, nil
+		case "secp256k1":
+			return &signingMethodES256K{SigningMethodECDSA: jwt.SigningMethodES256}, &key.PublicKey, nil
+		default:
+			return nil, nil, ErrUnsupportedCurve
+		}
+	case *rsa.PrivateKey:
+		// RFC 9449 recommends RSA-PSS over PKCS#1 v1.5; match the PS256/384/512 algorithms
+		// this package accepts when parsing.
+		return jwt.SigningMethodPS256, &key.PublicKey, nil
+	case ed25519.PrivateKey:
+		return jwt.SigningMethodEdDSA, key.Public(), nil
+	case ed448.PrivateKey:
+		return &signingMethodEd448{}, key.Public(), nil
+	default:
+		return nil, nil, ErrUnsupportedKeyAlgorithm
+	}
+}
+
+// newJTI generates a random `jti` claim value for a proof that doesn't specify one.
+func newJTI() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// athHash computes the `ath` claim value for an access token: base64url(sha256(accessToken)).
+// See https://datatracker.ietf.org/doc/html/rfc9449#section-4.3
+func athHash(accessToken string) string {
+	sum := sha256.Sum256([]byte(accessToken))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
@@ -0,0 +1,84 @@
+package dpop
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"net/url"
+	"testing"
+
+	"github.com/cloudflare/circl/sign/ed448"
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+)
+
+// TestCreateParseRoundTrip mints a proof for every key type this package supports and checks
+// that Parse accepts it and returns a non-empty `jkt`, covering the secp256k1 and Ed448 support
+// added alongside the curve-specific signing methods in curves.go.
+func TestCreateParseRoundTrip(t *testing.T) {
+	httpURL, err := url.Parse("https://example.com/resource")
+	if err != nil {
+		t.Fatalf("url.Parse: %v", err)
+	}
+
+	keys := map[string]interface{}{}
+
+	p256, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generate P-256 key: %v", err)
+	}
+	keys["P-256"] = p256
+
+	k256, err := ecdsa.GenerateKey(secp256k1.S256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generate secp256k1 key: %v", err)
+	}
+	keys["secp256k1"] = k256
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generate RSA key: %v", err)
+	}
+	keys["RSA"] = rsaKey
+
+	_, ed25519Priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("generate Ed25519 key: %v", err)
+	}
+	keys["Ed25519"] = ed25519Priv
+
+	_, ed448Priv, err := ed448.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("generate Ed448 key: %v", err)
+	}
+	keys["Ed448"] = ed448Priv
+
+	for name, privateKey := range keys {
+		name, privateKey := name, privateKey
+		t.Run(name, func(t *testing.T) {
+			proof, err := Create(privateKey, POST, httpURL, CreateOptions{})
+			if err != nil {
+				t.Fatalf("Create: %v", err)
+			}
+
+			parseOpts := ParseOptions{}
+			switch name {
+			case "secp256k1":
+				// ES256K is deliberately excluded from DefaultAllowedAlgorithms.
+				parseOpts.AllowedAlgorithms = []string{"ES256K"}
+			case "Ed448":
+				// Ed448 is deliberately excluded from DefaultAllowedAlgorithms.
+				parseOpts.AllowedAlgorithms = []string{"Ed448"}
+			}
+
+			parsed, err := Parse(proof, POST, httpURL, parseOpts)
+			if err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+			if parsed.HashedPublicKey == "" {
+				t.Fatal("Parse returned an empty jkt")
+			}
+		})
+	}
+}
@@ -0,0 +1,74 @@
+package dpop
+
+import (
+	"crypto/ecdsa"
+
+	"github.com/cloudflare/circl/sign/ed448"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// signingMethodES256K implements "ES256K", the secp256k1/ECDSA-SHA256 algorithm used by
+// Bluesky/atproto and several OIDC ecosystems. It delegates to the same ECDSA logic as
+// jwt.SigningMethodES256 but is restricted to the secp256k1 curve, since the two algorithms
+// would otherwise be indistinguishable to the jwt package by key type alone.
+type signingMethodES256K struct {
+	*jwt.SigningMethodECDSA
+}
+
+// Alg reports "ES256K", not the embedded ES256 method's "ES256" - otherwise
+// jwt.GetSigningMethod("ES256K").Alg() would return the wrong name and every caller matching
+// alg against crv (see validateAlgMatchesJwk) would reject legitimate secp256k1 proofs.
+func (m *signingMethodES256K) Alg() string { return "ES256K" }
+
+func (m *signingMethodES256K) Verify(signingString string, sig []byte, key interface{}) error {
+	if pub, ok := key.(*ecdsa.PublicKey); ok && pub.Curve.Params().Name != "secp256k1" {
+		return ErrUnsupportedCurve
+	}
+	return m.SigningMethodECDSA.Verify(signingString, sig, key)
+}
+
+func (m *signingMethodES256K) Sign(signingString string, key interface{}) ([]byte, error) {
+	if priv, ok := key.(*ecdsa.PrivateKey); ok && priv.Curve.Params().Name != "secp256k1" {
+		return nil, ErrUnsupportedCurve
+	}
+	return m.SigningMethodECDSA.Sign(signingString, key)
+}
+
+// signingMethodEd448 implements "Ed448". JOSE has no alg name for Ed448 distinct from
+// Ed25519's "EdDSA", and the two aren't interchangeable at the jwt package's built-in "EdDSA"
+// method, which only verifies ed25519.PublicKey. Rather than override that method -
+// jwt.RegisterSigningMethod mutates a process-wide global registry, and "EdDSA" belongs to the
+// jwt package, not this one - Ed448 is registered under its own alg name instead. A DPoP proof
+// over an Ed448 key therefore carries `alg: "Ed448"`, which callers must opt into via
+// ParseOptions.AllowedAlgorithms since it isn't part of DefaultAllowedAlgorithms.
+type signingMethodEd448 struct{}
+
+func (m *signingMethodEd448) Alg() string { return "Ed448" }
+
+func (m *signingMethodEd448) Verify(signingString string, sig []byte, key interface{}) error {
+	pub, ok := key.(ed448.PublicKey)
+	if !ok {
+		return jwt.ErrInvalidKeyType
+	}
+	if !ed448.Verify(pub, []byte(signingString), sig, "") {
+		return jwt.ErrSignatureInvalid
+	}
+	return nil
+}
+
+func (m *signingMethodEd448) Sign(signingString string, key interface{}) ([]byte, error) {
+	priv, ok := key.(ed448.PrivateKey)
+	if !ok {
+		return nil, jwt.ErrInvalidKeyType
+	}
+	return ed448.Sign(priv, []byte(signingString), ""), nil
+}
+
+func init() {
+	jwt.RegisterSigningMethod("ES256K", func() jwt.SigningMethod {
+		return &signingMethodES256K{SigningMethodECDSA: jwt.SigningMethodES256}
+	})
+	jwt.RegisterSigningMethod("Ed448", func() jwt.SigningMethod {
+		return &signingMethodEd448{}
+	})
+}
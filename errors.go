@@ -0,0 +1,22 @@
+package dpop
+
+import "errors"
+
+// Errors returned by Parse, Create, and Proof.Validate.
+//
+// Parse and Validate typically return these joined with errors.Join alongside a more general
+// sentinel (ErrInvalidProof); use errors.Is to test for a specific cause.
+var (
+	ErrInvalidProof            = errors.New("dpop: invalid proof")
+	ErrMissingClaims           = errors.New("dpop: proof is missing required claims")
+	ErrUnsupportedJWTType      = errors.New("dpop: unsupported JWT \"typ\" header")
+	ErrIncorrectHTTPTarget     = errors.New("dpop: proof htm/htu does not match the request")
+	ErrIncorrectNonce          = errors.New("dpop: proof nonce does not match the expected nonce")
+	ErrExpired                 = errors.New("dpop: proof iat is too far in the past")
+	ErrFuture                  = errors.New("dpop: proof iat is too far in the future")
+	ErrMissingJWK              = errors.New("dpop: proof is missing a jwk header")
+	ErrIncorrectJKT            = errors.New("dpop: proof key does not match the expected jkt")
+	ErrUnsupportedCurve        = errors.New("dpop: unsupported EC curve")
+	ErrUnsupportedKeyAlgorithm = errors.New("dpop: unsupported key algorithm")
+	ErrIncorrectAth            = errors.New("dpop: proof ath does not match the access token")
+)
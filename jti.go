@@ -0,0 +1,117 @@
+package dpop
+
+import (
+	"container/heap"
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrReplayedProof is returned by Parse when a proof's `jti` has already been observed by the
+// configured JTIStore within its allowed age window.
+var ErrReplayedProof = errors.New("dpop: proof has already been used")
+
+// JTIStore enforces one-time-use of DPoP proofs by tracking which `jti` claims have already
+// been seen, per https://datatracker.ietf.org/doc/html/rfc9449#section-11.1.
+type JTIStore interface {
+	// SeenBefore records that jti was presented in a proof whose `iat`/age window expires at
+	// exp, and reports whether jti has already been seen before this call. Implementations
+	// should evict entries once exp has passed.
+	SeenBefore(ctx context.Context, jti string, exp time.Time) (bool, error)
+}
+
+// jtiEntry is a JTIStore record ordered by expiry for eviction.
+type jtiEntry struct {
+	jti string
+	exp time.Time
+}
+
+// jtiHeap is a container/heap.Interface min-heap of jtiEntry ordered by exp, so the earliest
+// expiring entry is always at the root and can be evicted in O(log n).
+type jtiHeap []*jtiEntry
+
+func (h jtiHeap) Len() int            { return len(h) }
+func (h jtiHeap) Less(i, j int) bool  { return h[i].exp.Before(h[j].exp) }
+func (h jtiHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *jtiHeap) Push(x interface{}) { *h = append(*h, x.(*jtiEntry)) }
+func (h *jtiHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	entry := old[n-1]
+	*h = old[:n-1]
+	return entry
+}
+
+// MemoryJTIStore is an in-memory JTIStore suitable for a single server instance. Seen jtis are
+// tracked in a min-heap keyed by expiry so that evicting expired entries is O(log n) rather
+// than a full scan of every jti ever seen.
+type MemoryJTIStore struct {
+	mu    sync.Mutex
+	seen  map[string]struct{}
+	queue jtiHeap
+}
+
+func (s *MemoryJTIStore) SeenBefore(ctx context.Context, jti string, exp time.Time) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.evictExpired()
+
+	if s.seen == nil {
+		s.seen = make(map[string]struct{})
+	}
+	if _, ok := s.seen[jti]; ok {
+		return true, nil
+	}
+
+	s.seen[jti] = struct{}{}
+	heap.Push(&s.queue, &jtiEntry{jti: jti, exp: exp})
+	return false, nil
+}
+
+// evictExpired removes entries whose exp has passed. Callers must hold s.mu.
+func (s *MemoryJTIStore) evictExpired() {
+	now := time.Now()
+	for s.queue.Len() > 0 && s.queue[0].exp.Before(now) {
+		entry := heap.Pop(&s.queue).(*jtiEntry)
+		delete(s.seen, entry.jti)
+	}
+}
+
+// RedisSetter is the subset of a Redis client RedisJTIStore needs. Callers pass their own
+// *redis.Client (github.com/redis/go-redis/v9) or equivalent wrapper satisfying this
+// interface, so this package does not depend on a specific Redis library.
+type RedisSetter interface {
+	// SetNX should behave like Redis SETNX: it sets key to value with the given expiry and
+	// reports true only if the key did not already exist.
+	SetNX(ctx context.Context, key string, value interface{}, ttl time.Duration) (bool, error)
+}
+
+// RedisJTIStore adapts a Redis client to JTIStore, suitable for multi-instance deployments
+// where a process-local MemoryJTIStore would allow a proof to be replayed against a different
+// instance.
+type RedisJTIStore struct {
+	Client RedisSetter
+
+	// Prefix is prepended to every jti when forming the Redis key. Defaults to "dpop:jti:".
+	Prefix string
+}
+
+func (s *RedisJTIStore) SeenBefore(ctx context.Context, jti string, exp time.Time) (bool, error) {
+	prefix := s.Prefix
+	if prefix == "" {
+		prefix = "dpop:jti:"
+	}
+
+	ttl := time.Until(exp)
+	if ttl <= 0 {
+		ttl = time.Second
+	}
+
+	notSeen, err := s.Client.SetNX(ctx, prefix+jti, "1", ttl)
+	if err != nil {
+		return false, err
+	}
+	return !notSeen, nil
+}
@@ -0,0 +1,71 @@
+package dpop
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// TestMemoryJTIStoreRejectsReplay checks the one-time-use guarantee SeenBefore is meant to
+// provide: the same jti is accepted once and rejected on every subsequent call within its
+// expiry.
+func TestMemoryJTIStoreRejectsReplay(t *testing.T) {
+	store := &MemoryJTIStore{}
+	exp := time.Now().Add(time.Minute)
+
+	seen, err := store.SeenBefore(context.Background(), "jti-1", exp)
+	if err != nil {
+		t.Fatalf("SeenBefore: %v", err)
+	}
+	if seen {
+		t.Fatal("first SeenBefore call reported the jti as already seen")
+	}
+
+	seen, err = store.SeenBefore(context.Background(), "jti-1", exp)
+	if err != nil {
+		t.Fatalf("SeenBefore: %v", err)
+	}
+	if !seen {
+		t.Fatal("replayed jti was not reported as seen")
+	}
+}
+
+// TestMemoryJTIStoreEvictsExpired checks that an entry past its exp no longer counts as seen,
+// so the heap is actually pruning rather than growing without bound.
+func TestMemoryJTIStoreEvictsExpired(t *testing.T) {
+	store := &MemoryJTIStore{}
+
+	if _, err := store.SeenBefore(context.Background(), "jti-1", time.Now().Add(-time.Second)); err != nil {
+		t.Fatalf("SeenBefore: %v", err)
+	}
+
+	seen, err := store.SeenBefore(context.Background(), "jti-1", time.Now().Add(time.Minute))
+	if err != nil {
+		t.Fatalf("SeenBefore: %v", err)
+	}
+	if seen {
+		t.Fatal("expired jti should not still be reported as seen")
+	}
+}
+
+// TestParseRejectsReplayedJTI exercises JTIStore through Parse end-to-end.
+func TestParseRejectsReplayedJTI(t *testing.T) {
+	privateKey := generateP256TestKey(t)
+	httpURL := mustParseURL(t, "https://example.com/resource")
+	store := &MemoryJTIStore{}
+
+	proof, err := Create(privateKey, GET, httpURL, CreateOptions{})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	if _, err := Parse(proof, GET, httpURL, ParseOptions{JTIStore: store}); err != nil {
+		t.Fatalf("first Parse: %v", err)
+	}
+
+	_, err = Parse(proof, GET, httpURL, ParseOptions{JTIStore: store})
+	if !errors.Is(err, ErrReplayedProof) {
+		t.Fatalf("expected ErrReplayedProof, got %v", err)
+	}
+}
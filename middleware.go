@@ -0,0 +1,141 @@
+package dpop
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// URLResolver reconstructs the externally-visible URL of an incoming request, which can
+// differ from r.URL when the server sits behind a reverse proxy or load balancer.
+type URLResolver func(r *http.Request) *url.URL
+
+// DefaultURLResolver reconstructs the request URL from the `X-Forwarded-Proto` and
+// `X-Forwarded-Host` headers when present, falling back to r.TLS and r.Host.
+func DefaultURLResolver(r *http.Request) *url.URL {
+	scheme := r.Header.Get("X-Forwarded-Proto")
+	if scheme == "" {
+		if r.TLS != nil {
+			scheme = "https"
+		} else {
+			scheme = "http"
+		}
+	}
+
+	host := r.Header.Get("X-Forwarded-Host")
+	if host == "" {
+		host = r.Host
+	}
+
+	resolved := *r.URL
+	resolved.Scheme = scheme
+	resolved.Host = host
+	return &resolved
+}
+
+// MiddlewareOptions configures Middleware.
+type MiddlewareOptions struct {
+	// ParseOptions is passed through to Parse for every request.
+	ParseOptions ParseOptions
+
+	// URLResolver reconstructs the effective request URL used for the `htu` check. Defaults
+	// to DefaultURLResolver.
+	URLResolver URLResolver
+
+	// NonceProvider, if set, is used to issue a `DPoP-Nonce` header on every rejected
+	// request, per https://datatracker.ietf.org/doc/html/rfc9449#section-8.
+	NonceProvider NonceProvider
+
+	// RequireAccessTokenBinding, if true, requires an `Authorization: DPoP <token>` header
+	// and validates the proof's `ath`/`jkt` binding against it via Proof.Validate. BoundJKT
+	// must be set when this is true.
+	RequireAccessTokenBinding bool
+
+	// BoundJKT returns the `cnf.jkt` confirmation value for the given access token, e.g. by
+	// inspecting its JWT claims or calling token introspection. Required when
+	// RequireAccessTokenBinding is true.
+	BoundJKT func(accessToken string) (string, error)
+}
+
+type contextKey int
+
+const proofContextKey contextKey = 0
+
+// FromContext returns the *Proof validated by Middleware for this request, if any.
+func FromContext(ctx context.Context) (*Proof, bool) {
+	proof, ok := ctx.Value(proofContextKey).(*Proof)
+	return proof, ok
+}
+
+// Middleware returns net/http middleware for a resource server that validates the `DPoP`
+// header of incoming requests with Parse (and, when configured, Proof.Validate), rejecting
+// invalid proofs with the `WWW-Authenticate: DPoP` challenge described in
+// https://datatracker.ietf.org/doc/html/rfc9449#section-5. The validated proof is made
+// available to the wrapped handler via FromContext.
+func Middleware(opts MiddlewareOptions) func(http.Handler) http.Handler {
+	resolver := opts.URLResolver
+	if resolver == nil {
+		resolver = DefaultURLResolver
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			proofHeader := r.Header.Get("DPoP")
+			if proofHeader == "" {
+				writeDPoPChallenge(w, opts.NonceProvider, r.Context(), "invalid_dpop_proof", "missing DPoP header")
+				return
+			}
+
+			proof, err := Parse(proofHeader, HTTPVerb(r.Method), resolver(r), opts.ParseOptions)
+			if err != nil {
+				writeDPoPChallenge(w, opts.NonceProvider, r.Context(), "invalid_dpop_proof", "the DPoP proof is invalid")
+				return
+			}
+
+			if opts.RequireAccessTokenBinding {
+				accessToken, ok := bearerDPoPToken(r)
+				if !ok {
+					writeDPoPChallenge(w, opts.NonceProvider, r.Context(), "invalid_token", "missing DPoP-bound access token")
+					return
+				}
+				boundJKT, err := opts.BoundJKT(accessToken)
+				if err != nil {
+					writeDPoPChallenge(w, opts.NonceProvider, r.Context(), "invalid_token", "unable to validate the access token")
+					return
+				}
+				if err := proof.Validate(accessToken, boundJKT); err != nil {
+					writeDPoPChallenge(w, opts.NonceProvider, r.Context(), "invalid_token", "the DPoP proof does not match the access token")
+					return
+				}
+			}
+
+			ctx := context.WithValue(r.Context(), proofContextKey, proof)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// bearerDPoPToken extracts the access token from an `Authorization: DPoP <token>` header, per
+// https://datatracker.ietf.org/doc/html/rfc9449#section-7.
+func bearerDPoPToken(r *http.Request) (string, bool) {
+	const prefix = "DPoP "
+	auth := r.Header.Get("Authorization")
+	if !strings.HasPrefix(auth, prefix) {
+		return "", false
+	}
+	return strings.TrimPrefix(auth, prefix), true
+}
+
+// writeDPoPChallenge writes a 401 response carrying a `WWW-Authenticate: DPoP` challenge, and
+// a fresh `DPoP-Nonce` header when provider is set.
+func writeDPoPChallenge(w http.ResponseWriter, provider NonceProvider, ctx context.Context, errCode string, description string) {
+	if provider != nil {
+		if nonce, err := provider.Issue(ctx); err == nil {
+			w.Header().Set("DPoP-Nonce", nonce)
+		}
+	}
+
+	w.Header().Set("WWW-Authenticate", `DPoP error="`+errCode+`", error_description="`+description+`"`)
+	w.WriteHeader(http.StatusUnauthorized)
+}
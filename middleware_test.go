@@ -0,0 +1,103 @@
+package dpop
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// TestMiddlewareDoesNotLeakInternalErrorText ensures a malformed DPoP header - which fails
+// deep inside the jwt library with a message like "token contains an invalid number of
+// segments" - produces only a generic, fixed WWW-Authenticate description.
+func TestMiddlewareDoesNotLeakInternalErrorText(t *testing.T) {
+	handler := Middleware(MiddlewareOptions{})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Fatal("next handler should not run for an invalid proof")
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "https://example.com/resource", nil)
+	req.Header.Set("DPoP", "not-a-jwt")
+	rec := httptest.NewRecorder()
+
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401, got %d", rec.Code)
+	}
+
+	challenge := rec.Header().Get("WWW-Authenticate")
+	if strings.Contains(strings.ToLower(challenge), "segment") {
+		t.Fatalf("WWW-Authenticate leaked internal jwt library error text: %q", challenge)
+	}
+	if !strings.Contains(challenge, `error="invalid_dpop_proof"`) {
+		t.Fatalf("expected invalid_dpop_proof error code, got %q", challenge)
+	}
+}
+
+// TestMiddlewareRequireAccessTokenBinding exercises the RequireAccessTokenBinding +
+// BoundJKT + Proof.Validate path: a request carrying a DPoP-bound access token should only
+// reach the wrapped handler when the proof's `ath`/`jkt` actually match that token.
+func TestMiddlewareRequireAccessTokenBinding(t *testing.T) {
+	const accessToken = "opaque-access-token"
+
+	privateKey := generateP256TestKey(t)
+	httpURL := mustParseURL(t, "https://example.com/resource")
+	proofStr, err := Create(privateKey, GET, httpURL, CreateOptions{AccessToken: accessToken})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	matchingJKT, err := Parse(proofStr, GET, httpURL, ParseOptions{})
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+
+	newRequest := func() *http.Request {
+		req := httptest.NewRequest(http.MethodGet, "https://example.com/resource", nil)
+		req.Header.Set("DPoP", proofStr)
+		req.Header.Set("Authorization", "DPoP "+accessToken)
+		return req
+	}
+
+	t.Run("matching jkt succeeds", func(t *testing.T) {
+		var calledWithProof *Proof
+		handler := Middleware(MiddlewareOptions{
+			RequireAccessTokenBinding: true,
+			BoundJKT: func(token string) (string, error) {
+				return matchingJKT.HashedPublicKey, nil
+			},
+		})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			calledWithProof, _ = FromContext(r.Context())
+		}))
+
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, newRequest())
+
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d with %q", rec.Code, rec.Header().Get("WWW-Authenticate"))
+		}
+		if calledWithProof == nil {
+			t.Fatal("expected the wrapped handler to run with the validated proof in context")
+		}
+	})
+
+	t.Run("mismatched jkt is rejected", func(t *testing.T) {
+		handler := Middleware(MiddlewareOptions{
+			RequireAccessTokenBinding: true,
+			BoundJKT: func(token string) (string, error) {
+				return "not-the-right-jkt", nil
+			},
+		})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			t.Fatal("next handler should not run when the jkt does not match")
+		}))
+
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, newRequest())
+
+		if rec.Code != http.StatusUnauthorized {
+			t.Fatalf("expected 401, got %d", rec.Code)
+		}
+		if !strings.Contains(rec.Header().Get("WWW-Authenticate"), `error="invalid_token"`) {
+			t.Fatalf("expected invalid_token error code, got %q", rec.Header().Get("WWW-Authenticate"))
+		}
+	})
+}
@@ -0,0 +1,211 @@
+package dpop
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// ErrInvalidNonce is returned by a NonceProvider when a nonce is unknown, malformed, expired,
+// or already used.
+var ErrInvalidNonce = errors.New("dpop: invalid or expired nonce")
+
+// NonceProvider issues and verifies server-managed DPoP nonces, per
+// https://datatracker.ietf.org/doc/html/rfc9449#section-8.
+type NonceProvider interface {
+	// Issue returns a new nonce to send to the client in a `DPoP-Nonce` header.
+	Issue(ctx context.Context) (string, error)
+
+	// Verify checks that nonce was previously issued by Issue and is still valid, and
+	// returns the time it was issued. With NonceHasTimestamp set, Parse checks the proof's
+	// `iat` against that time, rather than against the current time, to enforce the proof age.
+	Verify(ctx context.Context, nonce string) (issuedAt time.Time, err error)
+}
+
+// HMACNonceProvider is a stateless NonceProvider: a nonce is base64url(timestamp || mac),
+// where mac authenticates the timestamp under Key. It requires no storage, at the cost that a
+// nonce remains valid - and so can be reused - for the rest of its TTL; pair it with a
+// JTIStore on the proofs that carry it if single use is required.
+type HMACNonceProvider struct {
+	// Key is the HMAC-SHA256 signing key. It must be set.
+	Key []byte
+
+	// TTL is how long an issued nonce remains valid. Defaults to DEFAULT_ALLOWED_PROOF_AGE.
+	TTL time.Duration
+}
+
+func (p *HMACNonceProvider) Issue(ctx context.Context) (string, error) {
+	return p.sign(time.Now()), nil
+}
+
+func (p *HMACNonceProvider) Verify(ctx context.Context, nonce string) (time.Time, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(nonce)
+	if err != nil || len(raw) <= 8 {
+		return time.Time{}, ErrInvalidNonce
+	}
+	ts, mac := raw[:8], raw[8:]
+
+	if !hmac.Equal(mac, p.mac(ts)) {
+		return time.Time{}, ErrInvalidNonce
+	}
+
+	issuedAt := time.Unix(int64(binary.BigEndian.Uint64(ts)), 0)
+	if time.Since(issuedAt) > p.ttl() {
+		return time.Time{}, ErrInvalidNonce
+	}
+	return issuedAt, nil
+}
+
+func (p *HMACNonceProvider) sign(t time.Time) string {
+	ts := make([]byte, 8)
+	binary.BigEndian.PutUint64(ts, uint64(t.Unix()))
+	return base64.RawURLEncoding.EncodeToString(append(ts, p.mac(ts)...))
+}
+
+func (p *HMACNonceProvider) mac(ts []byte) []byte {
+	h := hmac.New(sha256.New, p.Key)
+	h.Write(ts)
+	return h.Sum(nil)
+}
+
+func (p *HMACNonceProvider) ttl() time.Duration {
+	if p.TTL == 0 {
+		return DEFAULT_ALLOWED_PROOF_AGE
+	}
+	return p.TTL
+}
+
+// MemoryNonceProvider is an in-memory, single-use NonceProvider suitable for a single server
+// instance. Each nonce returned by Issue may be consumed by Verify exactly once, guarding
+// against replay in a way the stateless HMACNonceProvider cannot.
+type MemoryNonceProvider struct {
+	// TTL is how long an issued nonce remains valid before it is pruned. Defaults to
+	// DEFAULT_ALLOWED_PROOF_AGE.
+	TTL time.Duration
+
+	mu     sync.Mutex
+	issued map[string]time.Time
+}
+
+func (p *MemoryNonceProvider) Issue(ctx context.Context) (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	nonce := base64.RawURLEncoding.EncodeToString(buf)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.issued == nil {
+		p.issued = make(map[string]time.Time)
+	}
+	p.prune()
+	p.issued[nonce] = time.Now()
+	return nonce, nil
+}
+
+func (p *MemoryNonceProvider) Verify(ctx context.Context, nonce string) (time.Time, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.prune()
+
+	issuedAt, ok := p.issued[nonce]
+	if !ok {
+		return time.Time{}, ErrInvalidNonce
+	}
+	delete(p.issued, nonce)
+	return issuedAt, nil
+}
+
+func (p *MemoryNonceProvider) ttl() time.Duration {
+	if p.TTL == 0 {
+		return DEFAULT_ALLOWED_PROOF_AGE
+	}
+	return p.TTL
+}
+
+// prune removes expired nonces. Callers must hold p.mu.
+func (p *MemoryNonceProvider) prune() {
+	ttl := p.ttl()
+	for nonce, issuedAt := range p.issued {
+		if time.Since(issuedAt) > ttl {
+			delete(p.issued, nonce)
+		}
+	}
+}
+
+// nonceIssuedAtKey is the request context key NonceMiddleware uses to hand the issuedAt time
+// of a nonce it has already verified (and, for a one-time-use provider, already consumed) to
+// the handler, so a subsequent Parse call doesn't have to call NonceProvider.Verify again.
+type nonceIssuedAtKey struct{}
+
+// NonceIssuedAtFromContext returns the issuedAt time NonceMiddleware recovered for this
+// request's nonce, if any. Pass it to Parse via ParseOptions.NonceIssuedAt instead of also
+// setting ParseOptions.NonceProvider, since a one-time-use NonceProvider (e.g.
+// MemoryNonceProvider) would otherwise reject the nonce the second time Verify is called.
+func NonceIssuedAtFromContext(ctx context.Context) (time.Time, bool) {
+	issuedAt, ok := ctx.Value(nonceIssuedAtKey{}).(time.Time)
+	return issuedAt, ok
+}
+
+// NonceMiddleware requires that every request carrying a `DPoP` header present a nonce
+// previously issued by provider, and issues a fresh nonce on every response so clients can
+// always pick one up. Requests with a missing or invalid nonce are rejected with a 401
+// response carrying `WWW-Authenticate: DPoP error="use_dpop_nonce"`, per
+// https://datatracker.ietf.org/doc/html/rfc9449#section-8.
+//
+// This middleware only handles the nonce challenge-response round trip; it does not verify
+// the proof's signature or other claims. The nonce's issuedAt time, once verified here, is
+// made available to next via NonceIssuedAtFromContext so that a subsequent
+// Parse(..., ParseOptions{NonceHasTimestamp: true, NonceIssuedAt: &issuedAt}) call in the
+// wrapped handler doesn't need to (and, against a one-time-use NonceProvider, must not) call
+// Verify on the same nonce a second time.
+func NonceMiddleware(provider NonceProvider, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if nonce, err := provider.Issue(r.Context()); err == nil {
+			w.Header().Set("DPoP-Nonce", nonce)
+		}
+
+		if proofHeader := r.Header.Get("DPoP"); proofHeader != "" {
+			nonce, err := unverifiedProofNonce(proofHeader)
+			if err != nil || nonce == "" {
+				writeUseDPoPNonce(w)
+				return
+			}
+			issuedAt, err := provider.Verify(r.Context(), nonce)
+			if err != nil {
+				writeUseDPoPNonce(w)
+				return
+			}
+			r = r.WithContext(context.WithValue(r.Context(), nonceIssuedAtKey{}, issuedAt))
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// unverifiedProofNonce extracts the `nonce` claim from a DPoP proof without verifying its
+// signature. This is safe here because NonceMiddleware only uses the result to decide whether
+// to reject with a nonce challenge; the proof is fully verified by Parse once the client
+// retries with a valid nonce.
+func unverifiedProofNonce(proofHeader string) (string, error) {
+	claims := ProofTokenClaims{RegisteredClaims: &jwt.RegisteredClaims{}}
+	if _, _, err := jwt.NewParser().ParseUnverified(proofHeader, &claims); err != nil {
+		return "", err
+	}
+	return claims.Nonce, nil
+}
+
+func writeUseDPoPNonce(w http.ResponseWriter) {
+	w.Header().Set("WWW-Authenticate", `DPoP error="use_dpop_nonce", error_description="Resource server requires nonce in DPoP proof"`)
+	w.WriteHeader(http.StatusUnauthorized)
+}
@@ -0,0 +1,78 @@
+package dpop
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+// TestNonceMiddlewareHandsOffIssuedAtToParse reproduces a client that fetches a nonce,
+// builds a proof with it, and retries: NonceMiddleware verifies (and, for a one-time-use
+// provider, consumes) the nonce once, and Parse inside the wrapped handler must reuse that
+// result via ParseOptions.NonceIssuedAt rather than calling Verify again.
+func TestNonceMiddlewareHandsOffIssuedAtToParse(t *testing.T) {
+	provider := &MemoryNonceProvider{}
+
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	httpURL, err := url.Parse("https://example.com/resource")
+	if err != nil {
+		t.Fatalf("url.Parse: %v", err)
+	}
+
+	nonce, err := provider.Issue(context.Background())
+	if err != nil {
+		t.Fatalf("Issue: %v", err)
+	}
+
+	proof, err := Create(privateKey, POST, httpURL, CreateOptions{Nonce: nonce})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	var parseErr error
+	handler := NonceMiddleware(provider, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		opts := ParseOptions{NonceHasTimestamp: true}
+		if issuedAt, ok := NonceIssuedAtFromContext(r.Context()); ok {
+			opts.NonceIssuedAt = &issuedAt
+		}
+		_, parseErr = Parse(proof, POST, httpURL, opts)
+	}))
+
+	req := httptest.NewRequest(http.MethodPost, "https://example.com/resource", nil)
+	req.Header.Set("DPoP", proof)
+	rec := httptest.NewRecorder()
+
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("middleware rejected the request: %d %s", rec.Code, rec.Header().Get("WWW-Authenticate"))
+	}
+	if parseErr != nil {
+		t.Fatalf("Parse rejected a proof already verified by NonceMiddleware: %v", parseErr)
+	}
+}
+
+// TestMemoryNonceProviderSingleUse checks that a nonce cannot be verified twice.
+func TestMemoryNonceProviderSingleUse(t *testing.T) {
+	provider := &MemoryNonceProvider{}
+
+	nonce, err := provider.Issue(context.Background())
+	if err != nil {
+		t.Fatalf("Issue: %v", err)
+	}
+
+	if _, err := provider.Verify(context.Background(), nonce); err != nil {
+		t.Fatalf("first Verify: %v", err)
+	}
+	if _, err := provider.Verify(context.Background(), nonce); err == nil {
+		t.Fatal("second Verify of the same nonce should fail")
+	}
+}
@@ -1,6 +1,7 @@
 package dpop
 
 import (
+	"context"
 	"crypto/ecdsa"
 	"crypto/ed25519"
 	"crypto/elliptic"
@@ -14,6 +15,8 @@ import (
 	"strings"
 	"time"
 
+	"github.com/cloudflare/circl/sign/ed448"
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
 	"github.com/golang-jwt/jwt/v5"
 )
 
@@ -55,6 +58,36 @@ type ParseOptions struct {
 	// dpop_jkt parameter that is optionally sent by the client to the authorization server on token request.
 	// If set the proof proof-of-possession public key needs to match or the proof is rejected.
 	JKT string
+
+	// NonceProvider, if set together with NonceHasTimestamp, is used to recover the time the
+	// proof's nonce was issued at and reject the proof as expired if its iat is more than
+	// AllowedProofAge before that time, instead of skipping the check entirely. Prefer NonceIssuedAt when NonceMiddleware has already
+	// verified the nonce: calling Verify again here against a one-time-use NonceProvider
+	// (e.g. MemoryNonceProvider) would always fail, since the nonce was already consumed.
+	NonceProvider NonceProvider
+
+	// NonceIssuedAt, if set together with NonceHasTimestamp, is used directly as that time
+	// instead of calling NonceProvider.Verify. Populate it from
+	// NonceIssuedAtFromContext(r.Context()) when NonceMiddleware has already verified (and
+	// possibly consumed) the request's nonce.
+	NonceIssuedAt *time.Time
+
+	// JTIStore, if set, is used to reject proofs whose `jti` has already been observed
+	// within the allowed proof age window, enforcing one-time-use as required by
+	// https://datatracker.ietf.org/doc/html/rfc9449#section-11.1.
+	JTIStore JTIStore
+
+	// AllowedAlgorithms restricts which JWS `alg` values Parse will accept. Defaults to
+	// DefaultAllowedAlgorithms. "none" and HMAC algorithms are never accepted regardless of
+	// this setting, since a DPoP proof must be signed with the client's asymmetric key.
+	AllowedAlgorithms []string
+}
+
+// DefaultAllowedAlgorithms is used by Parse when ParseOptions.AllowedAlgorithms is nil.
+var DefaultAllowedAlgorithms = []string{
+	"ES256", "ES384", "ES512",
+	"PS256", "PS384", "PS512",
+	"EdDSA",
 }
 
 // Parse translates a DPoP proof string into a JWT token and parses it with the jwt package (github.com/golang-jwt/jwt/v5).
@@ -72,8 +105,13 @@ func Parse(
 	// Ensure that it is a well-formed JWT, that a supported signature algorithm is used,
 	// that it contains a public key, and that the signature verifies with the public key.
 	// This satisfies point 2, 5, 6 and 7 in https://datatracker.ietf.org/doc/html/rfc9449#section-4.3
+	allowedAlgorithms := opts.AllowedAlgorithms
+	if allowedAlgorithms == nil {
+		allowedAlgorithms = DefaultAllowedAlgorithms
+	}
+
 	claims := ProofTokenClaims{RegisteredClaims: &jwt.RegisteredClaims{}}
-	dpopToken, err := jwt.ParseWithClaims(tokenString, &claims, keyFunc)
+	dpopToken, err := jwt.ParseWithClaims(tokenString, &claims, keyFunc, jwt.WithValidMethods(allowedAlgorithms))
 	if err != nil {
 		return nil, errors.Join(ErrInvalidProof, err)
 	}
@@ -120,7 +158,28 @@ func Parse(
 
 	// Check that `iat` is within the acceptable window unless `nonce` contains a server managed timestamp.
 	// This satisfies point 11 in https://datatracker.ietf.org/doc/html/rfc9449#section-4.3
-	if !opts.NonceHasTimestamp {
+	if opts.NonceHasTimestamp && (opts.NonceIssuedAt != nil || opts.NonceProvider != nil) {
+		// When the nonce's issue time is known, `iat` is checked against that time instead of
+		// being skipped; only the proof age bound applies, TimeWindow is not used here.
+		var nonceIssuedAt time.Time
+		if opts.NonceIssuedAt != nil {
+			nonceIssuedAt = *opts.NonceIssuedAt
+		} else {
+			var err error
+			nonceIssuedAt, err = opts.NonceProvider.Verify(context.Background(), claims.Nonce)
+			if err != nil {
+				return nil, errors.Join(ErrInvalidProof, ErrInvalidNonce)
+			}
+		}
+
+		past := DEFAULT_ALLOWED_PROOF_AGE
+		if opts.AllowedProofAge != nil {
+			past = *opts.AllowedProofAge
+		}
+		if claims.IssuedAt.Before(nonceIssuedAt.Add(-past)) {
+			return nil, errors.Join(ErrInvalidProof, ErrExpired)
+		}
+	} else if !opts.NonceHasTimestamp {
 		// Check that `iat` is not too far into the past.
 		past := DEFAULT_ALLOWED_PROOF_AGE
 		if opts.AllowedProofAge != nil {
@@ -168,6 +227,22 @@ func Parse(
 		}
 	}
 
+	// Reject the proof if its `jti` has already been used within the allowed proof age
+	// window, per https://datatracker.ietf.org/doc/html/rfc9449#section-11.1.
+	if opts.JTIStore != nil {
+		past := DEFAULT_ALLOWED_PROOF_AGE
+		if opts.AllowedProofAge != nil {
+			past = *opts.AllowedProofAge
+		}
+		seen, err := opts.JTIStore.SeenBefore(context.Background(), claims.ID, claims.IssuedAt.Add(past))
+		if err != nil {
+			return nil, errors.Join(ErrInvalidProof, err)
+		}
+		if seen {
+			return nil, errors.Join(ErrInvalidProof, ErrReplayedProof)
+		}
+	}
+
 	return &Proof{
 		Token:           dpopToken,
 		HashedPublicKey: b64URLjwkHash,
@@ -187,9 +262,86 @@ func keyFunc(t *jwt.Token) (interface{}, error) {
 		return nil, ErrMissingJWK
 	}
 
+	kty, _ := jwkMap["kty"].(string)
+	crv, _ := jwkMap["crv"].(string)
+
+	if err := validateJwkMembers(jwkMap, kty); err != nil {
+		return nil, err
+	}
+	if err := validateAlgMatchesJwk(t.Method.Alg(), kty, crv); err != nil {
+		return nil, err
+	}
+
 	return parseJwk(jwkMap)
 }
 
+// requiredJwkMembers lists the JWK members allowed for each key type, per RFC 7638 section
+// 3.2 (and its OKP extension in RFC 8037). A DPoP proof's `jwk` header must contain only
+// these - in particular, private key material such as `d` must never be present.
+var requiredJwkMembers = map[string][]string{
+	"EC":  {"crv", "kty", "x", "y"},
+	"RSA": {"e", "kty", "n"},
+	"OKP": {"crv", "kty", "x"},
+}
+
+// validateJwkMembers rejects a JWK header containing any member other than the ones required
+// for its key type.
+func validateJwkMembers(jwkMap map[string]interface{}, kty string) error {
+	allowed, ok := requiredJwkMembers[kty]
+	if !ok {
+		return ErrUnsupportedKeyAlgorithm
+	}
+
+	for member := range jwkMap {
+		found := false
+		for _, a := range allowed {
+			if member == a {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return ErrInvalidProof
+		}
+	}
+	return nil
+}
+
+// validateAlgMatchesJwk rejects a proof whose JOSE `alg` header does not match the key type
+// (and, for EC/OKP keys, the curve) of its `jwk` header, so that e.g. an RSA key cannot be
+// verified under an unrelated EC algorithm.
+func validateAlgMatchesJwk(alg string, kty string, crv string) error {
+	switch kty {
+	case "EC":
+		expected, ok := map[string]string{
+			"P-256":     "ES256",
+			"P-384":     "ES384",
+			"P-521":     "ES512",
+			"secp256k1": "ES256K",
+		}[crv]
+		if !ok || alg != expected {
+			return ErrInvalidProof
+		}
+	case "RSA":
+		switch alg {
+		case "PS256", "PS384", "PS512":
+		default:
+			return ErrInvalidProof
+		}
+	case "OKP":
+		expected, ok := map[string]string{
+			"Ed25519": "EdDSA",
+			"Ed448":   "Ed448",
+		}[crv]
+		if !ok || alg != expected {
+			return ErrInvalidProof
+		}
+	default:
+		return ErrUnsupportedKeyAlgorithm
+	}
+	return nil
+}
+
 // Parses a JWK and inherently strips it of optional fields
 func parseJwk(jwkMap map[string]interface{}) (interface{}, error) {
 	// Ensure that JWK kty is present and is a string.
@@ -235,6 +387,8 @@ func parseJwk(jwkMap map[string]interface{}) (interface{}, error) {
 			curve = elliptic.P384()
 		case "P-521":
 			curve = elliptic.P521()
+		case "secp256k1":
+			curve = secp256k1.S256()
 		default:
 			return nil, ErrUnsupportedCurve
 		}
@@ -277,13 +431,24 @@ func parseJwk(jwkMap map[string]interface{}) (interface{}, error) {
 		if !ok {
 			return nil, ErrInvalidProof
 		}
+		crv, ok := jwkMap["crv"].(string)
+		if !ok {
+			return nil, ErrInvalidProof
+		}
 
 		publicKey, err := base64urlTrailingPadding(x)
 		if err != nil {
 			return nil, err
 		}
 
-		return ed25519.PublicKey(publicKey), nil
+		switch crv {
+		case "Ed25519":
+			return ed25519.PublicKey(publicKey), nil
+		case "Ed448":
+			return ed448.PublicKey(publicKey), nil
+		default:
+			return nil, ErrUnsupportedCurve
+		}
 	case "OCT":
 		return nil, ErrUnsupportedKeyAlgorithm
 	default:
@@ -347,9 +512,18 @@ func getKeyStringRepresentation(key interface{}) ([]byte, error) {
 			"crv": "Ed25519",
 			"x":   base64.RawURLEncoding.EncodeToString(key),
 		}
+	case ed448.PublicKey:
+		keyParts = map[string]interface{}{
+			"kty": "OKP",
+			"crv": "Ed448",
+			"x":   base64.RawURLEncoding.EncodeToString(key),
+		}
 	default:
 		return nil, ErrUnsupportedKeyAlgorithm
 	}
 
+	// json.Marshal sorts map[string]interface{} keys alphabetically, which happens to match
+	// the RFC 7638 thumbprint member order for every kty above (e.g. "crv","kty","x","y" for
+	// EC); no separate ordering step is needed.
 	return json.Marshal(keyParts)
 }
@@ -0,0 +1,60 @@
+package dpop
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// TestParseRejectsAlgorithmNotInAllowList checks that a proof signed with an algorithm
+// outside AllowedAlgorithms (here an ES256 proof from a P-256 key, parsed with only ES384
+// allowed) is rejected rather than silently accepted by whatever the jwt library supports for
+// that key type.
+func TestParseRejectsAlgorithmNotInAllowList(t *testing.T) {
+	privateKey := generateP256TestKey(t)
+	httpURL := mustParseURL(t, "https://example.com/resource")
+
+	proof, err := Create(privateKey, GET, httpURL, CreateOptions{})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	_, err = Parse(proof, GET, httpURL, ParseOptions{AllowedAlgorithms: []string{"ES384"}})
+	if err == nil {
+		t.Fatal("expected Parse to reject an ES256 proof when only ES384 is allowed")
+	}
+}
+
+// TestParseRejectsExtraJWKMembers checks that a `jwk` header carrying a member beyond what
+// RFC 7638 requires for its key type - in particular private key material like `d` - is
+// rejected instead of silently ignored.
+func TestParseRejectsExtraJWKMembers(t *testing.T) {
+	privateKey := generateP256TestKey(t)
+	httpURL := mustParseURL(t, "https://example.com/resource")
+
+	proof, err := Create(privateKey, GET, httpURL, CreateOptions{})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	parser := jwt.NewParser()
+	claims := ProofTokenClaims{RegisteredClaims: &jwt.RegisteredClaims{}}
+	token, _, err := parser.ParseUnverified(proof, &claims)
+	if err != nil {
+		t.Fatalf("ParseUnverified: %v", err)
+	}
+
+	jwkHeader, ok := token.Header["jwk"].(map[string]interface{})
+	if !ok {
+		t.Fatal("proof is missing a jwk header")
+	}
+	jwkHeader["d"] = "private-key-material-should-never-be-here"
+	token.Header["jwk"] = jwkHeader
+
+	if _, err := keyFunc(token); err == nil {
+		t.Fatal("expected keyFunc to reject a jwk header carrying \"d\"")
+	} else if !errors.Is(err, ErrInvalidProof) {
+		t.Fatalf("expected ErrInvalidProof, got %v", err)
+	}
+}
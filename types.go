@@ -0,0 +1,32 @@
+package dpop
+
+import "github.com/golang-jwt/jwt/v5"
+
+// ProofTokenClaims are the claims of a DPoP proof JWT.
+// See https://datatracker.ietf.org/doc/html/rfc9449#section-4.2
+type ProofTokenClaims struct {
+	*jwt.RegisteredClaims
+
+	// Method is the `htm` claim: the HTTP method of the request the proof is attached to.
+	Method HTTPVerb `json:"htm"`
+
+	// URL is the `htu` claim: the HTTP target URL of the request, without query or fragment.
+	URL string `json:"htu"`
+
+	// Nonce is the `nonce` claim, set once an authorization or resource server requires one.
+	Nonce string `json:"nonce,omitempty"`
+
+	// Ath is the `ath` claim: base64url(sha256(access_token)), present when the proof is
+	// bound to a previously issued access token.
+	Ath string `json:"ath,omitempty"`
+}
+
+// Proof is a DPoP proof that has been parsed and validated by Parse.
+type Proof struct {
+	// Token is the underlying JWT, including its header and claims.
+	Token *jwt.Token
+
+	// HashedPublicKey is the base64url-encoded SHA-256 JWK thumbprint of the proof's
+	// public key, i.e. the `jkt` confirmation value for this proof.
+	HashedPublicKey string
+}
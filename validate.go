@@ -0,0 +1,28 @@
+package dpop
+
+import "errors"
+
+// Validate checks that this proof is bound to the access token presented alongside it, as
+// required by https://datatracker.ietf.org/doc/html/rfc9449#section-4.3 point 12 for any
+// resource server request carrying a DPoP-bound access token.
+//
+// accessToken is the raw bearer token presented alongside the proof (the value of the
+// `Authorization: DPoP <token>` header). boundJKT is the `jkt` member of the access token's
+// `cnf` confirmation claim, as extracted by the caller from the token itself or from token
+// introspection - Validate does not parse the access token.
+func (p *Proof) Validate(accessToken string, boundJKT string) error {
+	claims, ok := p.Token.Claims.(*ProofTokenClaims)
+	if !ok {
+		return ErrInvalidProof
+	}
+
+	if claims.Ath == "" || claims.Ath != athHash(accessToken) {
+		return errors.Join(ErrInvalidProof, ErrIncorrectAth)
+	}
+
+	if p.HashedPublicKey == "" || p.HashedPublicKey != boundJKT {
+		return errors.Join(ErrInvalidProof, ErrIncorrectJKT)
+	}
+
+	return nil
+}
@@ -0,0 +1,97 @@
+package dpop
+
+import (
+	"errors"
+	"testing"
+)
+
+// TestValidateMatchingAthAndJKTSucceeds checks the happy path: a proof created with the
+// access token being presented, validated against the jkt of that same key, succeeds.
+func TestValidateMatchingAthAndJKTSucceeds(t *testing.T) {
+	privateKey := generateP256TestKey(t)
+	httpURL := mustParseURL(t, "https://example.com/resource")
+	const accessToken = "opaque-access-token"
+
+	proofStr, err := Create(privateKey, GET, httpURL, CreateOptions{AccessToken: accessToken})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	proof, err := Parse(proofStr, GET, httpURL, ParseOptions{})
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+
+	if err := proof.Validate(accessToken, proof.HashedPublicKey); err != nil {
+		t.Fatalf("expected Validate to succeed, got %v", err)
+	}
+}
+
+// TestValidateRejectsMismatchedAth checks that a proof bound to one access token fails
+// Validate when presented alongside a different one, which is what stops a stolen DPoP-bound
+// access token from being replayed with a proof minted for another token.
+func TestValidateRejectsMismatchedAth(t *testing.T) {
+	privateKey := generateP256TestKey(t)
+	httpURL := mustParseURL(t, "https://example.com/resource")
+
+	proofStr, err := Create(privateKey, GET, httpURL, CreateOptions{AccessToken: "token-a"})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	proof, err := Parse(proofStr, GET, httpURL, ParseOptions{})
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+
+	err = proof.Validate("token-b", proof.HashedPublicKey)
+	if !errors.Is(err, ErrIncorrectAth) {
+		t.Fatalf("expected ErrIncorrectAth, got %v", err)
+	}
+}
+
+// TestValidateRejectsMismatchedJKT checks that a proof is rejected when the caller's recorded
+// `cnf.jkt` for the access token does not match the key that actually signed the proof.
+func TestValidateRejectsMismatchedJKT(t *testing.T) {
+	privateKey := generateP256TestKey(t)
+	httpURL := mustParseURL(t, "https://example.com/resource")
+	const accessToken = "opaque-access-token"
+
+	proofStr, err := Create(privateKey, GET, httpURL, CreateOptions{AccessToken: accessToken})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	proof, err := Parse(proofStr, GET, httpURL, ParseOptions{})
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+
+	err = proof.Validate(accessToken, "not-the-right-jkt")
+	if !errors.Is(err, ErrIncorrectJKT) {
+		t.Fatalf("expected ErrIncorrectJKT, got %v", err)
+	}
+}
+
+// TestValidateRejectsMissingAth checks that a proof created without an AccessToken - and so
+// carrying no `ath` claim at all - is rejected by Validate rather than silently treated as
+// bound, which would let any proof satisfy the access-token-binding check.
+func TestValidateRejectsMissingAth(t *testing.T) {
+	privateKey := generateP256TestKey(t)
+	httpURL := mustParseURL(t, "https://example.com/resource")
+
+	proofStr, err := Create(privateKey, GET, httpURL, CreateOptions{})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	proof, err := Parse(proofStr, GET, httpURL, ParseOptions{})
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+
+	err = proof.Validate("some-access-token", proof.HashedPublicKey)
+	if !errors.Is(err, ErrIncorrectAth) {
+		t.Fatalf("expected ErrIncorrectAth for a proof with no ath claim, got %v", err)
+	}
+}